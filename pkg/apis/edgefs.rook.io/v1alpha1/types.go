@@ -0,0 +1,109 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	CustomResourceGroup = "edgefs.rook.io"
+	Version             = "v1alpha1"
+)
+
+// SWIFT represents a SWIFT gateway custom resource
+type SWIFT struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              SWIFTSpec   `json:"spec"`
+	Status            SWIFTStatus `json:"status,omitempty"`
+}
+
+// SWIFTStatus represents the observed state of a SWIFT gateway, as last reported by the
+// controller. It's updated via a regular Update rather than a status subresource, since the CRD
+// does not declare one.
+type SWIFTStatus struct {
+	// Phase summarizes the controller's most recent observation of the SWIFT gateway.
+	Phase SWIFTPhase `json:"phase,omitempty"`
+	// Message gives additional human-readable detail about Phase.
+	Message string `json:"message,omitempty"`
+}
+
+// SWIFTPhase summarizes the controller's most recent observation of a SWIFT gateway.
+type SWIFTPhase string
+
+const (
+	// SWIFTPhaseObserved indicates the controller is adopting this SWIFT gateway under
+	// ManagementPolicyObserve without creating, updating or deleting anything.
+	SWIFTPhaseObserved SWIFTPhase = "Observed"
+)
+
+// SWIFTList is a list of SWIFT custom resources
+type SWIFTList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SWIFT `json:"items"`
+}
+
+// SWIFTSpec represents the specification of a SWIFT gateway service
+type SWIFTSpec struct {
+	Instances       int32                   `json:"instances"`
+	Port            int32                   `json:"port,omitempty"`
+	SecurePort      int32                   `json:"securePort,omitempty"`
+	Options         map[string]string       `json:"options,omitempty"`
+	Placement       rookalpha.Placement     `json:"placement,omitempty"`
+	Resources       v1.ResourceRequirements `json:"resources,omitempty"`
+	ResourceProfile string                  `json:"resourceProfile,omitempty"`
+	ChunkCacheSize  resource.Quantity       `json:"chunkCacheSize,omitempty"`
+
+	// PreserveResourcesOnDeletion, when true, tells the operator to remove its OwnerReference
+	// from the StatefulSet/Service/ConfigMap backing this SWIFT gateway instead of deleting
+	// them when the CR is removed, leaving the gateway pods running. Useful for migrating to a
+	// new controller/cluster or rolling back an upgrade without dropping the object storage
+	// endpoint.
+	PreserveResourcesOnDeletion *bool `json:"preserveResourcesOnDeletion,omitempty"`
+
+	// ManagementPolicy controls how much the controller is allowed to mutate the SWIFT gateway
+	// resources it manages. Defaults to ManagementPolicyDefault (full lifecycle management).
+	ManagementPolicy ManagementPolicy `json:"managementPolicy,omitempty"`
+}
+
+// ManagementPolicy controls which lifecycle operations the SWIFTController performs for a given
+// SWIFT custom resource.
+type ManagementPolicy string
+
+const (
+	// ManagementPolicyDefault fully manages the SWIFT gateway resources: create, update and
+	// delete are all performed by the controller.
+	ManagementPolicyDefault ManagementPolicy = "Default"
+
+	// ManagementPolicyObserveCreateUpdate creates and updates the SWIFT gateway resources but
+	// skips deletion cleanup, leaving them in place when the CR is removed.
+	ManagementPolicyObserveCreateUpdate ManagementPolicy = "ObserveCreateUpdate"
+
+	// ManagementPolicyObserveDelete does not create or update the SWIFT gateway resources, but
+	// still cleans them up when the CR is removed. Useful for detaching from externally-managed
+	// gateways.
+	ManagementPolicyObserveDelete ManagementPolicy = "ObserveDelete"
+
+	// ManagementPolicyObserve adopts existing SWIFT gateway resources and only surfaces status;
+	// the controller never creates, updates or deletes anything.
+	ManagementPolicyObserve ManagementPolicy = "Observe"
+)
@@ -19,7 +19,9 @@ package swift
 
 import (
 	"fmt"
+	"net"
 	"reflect"
+	"sync"
 
 	"github.com/coreos/pkg/capnslog"
 	opkit "github.com/rook/operator-kit"
@@ -28,8 +30,12 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	"k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -38,6 +44,11 @@ const (
 	customResourceNamePlural = "swifts"
 )
 
+// instanceName and appName are declared in spec.go alongside CreateService/UpdateService/
+// DeleteService; releaseServiceOwnership reuses instanceName rather than re-deriving the
+// StatefulSet/Service/ConfigMap name so it can never drift out of sync with what those actually
+// create.
+
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "edgefs-op-swift")
 
 // SWIFTResource represents the swift custom resource
@@ -61,9 +72,12 @@ type SWIFTController struct {
 	resources       v1.ResourceRequirements
 	resourceProfile string
 	ownerRef        metav1.OwnerReference
+	labelSelector   labels.Selector
 }
 
-// NewSWIFTController create controller for watching SWIFT custom resources created
+// NewSWIFTController create controller for watching SWIFT custom resources created.
+// labelSelector scopes which SWIFT CRs this controller instance manages, allowing a single Rook
+// operator to shard SWIFT reconciliation across multiple replicas. Pass nil to manage all CRs.
 func NewSWIFTController(
 	context *clusterd.Context, rookImage string,
 	hostNetwork bool,
@@ -73,7 +87,11 @@ func NewSWIFTController(
 	resources v1.ResourceRequirements,
 	resourceProfile string,
 	ownerRef metav1.OwnerReference,
+	labelSelector labels.Selector,
 ) *SWIFTController {
+	if labelSelector == nil {
+		labelSelector = labels.Everything()
+	}
 	return &SWIFTController{
 		context:         context,
 		rookImage:       rookImage,
@@ -84,11 +102,26 @@ func NewSWIFTController(
 		resources:       resources,
 		resourceProfile: resourceProfile,
 		ownerRef:        ownerRef,
+		labelSelector:   labelSelector,
 	}
 }
 
-// StartWatch watches for instances of SWIFT custom resources and acts on them
-func (c *SWIFTController) StartWatch(namespace string, stopCh chan struct{}) error {
+// StartWatch watches for instances of SWIFT custom resources and acts on them. c.labelSelector is
+// pushed into the informer's list/watch options (see listWatch) so CRs that don't match are never
+// listed, watched or decoded by this controller instance, shifting their reconciliation to
+// whichever controller instance does own them. onAdd/onUpdate/onDelete still re-check
+// c.managesObject as a defensive second pass against labels changing after an object was cached.
+//
+// Watch errors are classified via a SetWatchErrorHandler installed on the informer's reflector
+// (mirroring the werf/kubedog approach). The reflector calls it when a list or the opening of a
+// watch fails; error events inside an already open watch stream are logged by the reflector itself
+// and followed by a re-list, and do not reach the handler. The returned
+// channel receives at most one error: transient errors (connection drops, expired resource
+// versions) are logged and left to the reflector's own re-list-with-backoff, while fatal errors
+// (missing CRD, RBAC forbidden, decode failures) are wrapped in an UnrecoverableWatchError, sent
+// once, and stop the informer. The caller is expected to cancel its context and restart the
+// controller when it receives from this channel.
+func (c *SWIFTController) StartWatch(namespace string, stopCh chan struct{}) (<-chan error, error) {
 
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
@@ -96,11 +129,129 @@ func (c *SWIFTController) StartWatch(namespace string, stopCh chan struct{}) err
 		DeleteFunc: c.onDelete,
 	}
 
-	logger.Infof("start watching swift resources in namespace %s", namespace)
-	watcher := opkit.NewWatcher(SWIFTResource, namespace, resourceHandlerFuncs, c.context.RookClientset.EdgefsV1alpha1().RESTClient())
-	go watcher.Watch(&edgefsv1alpha1.SWIFT{}, stopCh)
+	logger.Infof("start watching swift resources in namespace %s matching selector %s", namespace, c.labelSelector)
+	informer := cache.NewSharedIndexInformer(c.listWatch(namespace), &edgefsv1alpha1.SWIFT{}, 0, cache.Indexers{})
+	informer.AddEventHandler(resourceHandlerFuncs)
 
-	return nil
+	errCh := make(chan error, 1)
+	informerStopCh := make(chan struct{})
+	var stopOnce sync.Once
+	stop := func() { stopOnce.Do(func() { close(informerStopCh) }) }
+
+	// Forward the caller's shutdown signal without ever closing their channel ourselves.
+	go func() {
+		select {
+		case <-stopCh:
+			stop()
+		case <-informerStopCh:
+		}
+	}()
+
+	if err := informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		c.handleWatchError(r, err, errCh, stop)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to set swift watch error handler: %+v", err)
+	}
+
+	go informer.Run(informerStopCh)
+
+	return errCh, nil
+}
+
+// listWatch builds the ListWatch backing StartWatch's informer directly against the REST client,
+// bypassing opkit.NewWatcher, so c.labelSelector can be pushed into the list/watch options sent to
+// the API server: CRs that don't match are never listed, watched or decoded by this controller
+// instance in the first place, rather than being decoded and then dropped by managesObject.
+func (c *SWIFTController) listWatch(namespace string) *cache.ListWatch {
+	client := c.context.RookClientset.EdgefsV1alpha1().RESTClient()
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = c.labelSelector.String()
+			result := &edgefsv1alpha1.SWIFTList{}
+			err := client.Get().
+				Namespace(namespace).
+				Resource(SWIFTResource.Name).
+				VersionedParams(&options, metav1.ParameterCodec).
+				Do().
+				Into(result)
+			return result, err
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = c.labelSelector.String()
+			options.Watch = true
+			return client.Get().
+				Namespace(namespace).
+				Resource(SWIFTResource.Name).
+				VersionedParams(&options, metav1.ParameterCodec).
+				Watch()
+		},
+	}
+}
+
+// handleWatchError classifies an error surfaced by the informer's reflector. Transient errors are
+// logged and otherwise left alone, since the reflector re-lists and keeps watching on its own.
+// Fatal errors are wrapped in an UnrecoverableWatchError, sent once on errCh, and stop the
+// informer via stop so the caller can restart the controller from a clean state. Anything else
+// falls back to the reflector's default handling.
+func (c *SWIFTController) handleWatchError(r *cache.Reflector, err error, errCh chan<- error, stop func()) {
+	if err == nil {
+		return
+	}
+
+	if isFatalWatchError(err) {
+		unrecoverable := &UnrecoverableWatchError{ResName: SWIFTResource.Name, Err: err}
+		logger.Errorf("fatal error watching swift resources, stopping watch: %+v", unrecoverable)
+		select {
+		case errCh <- unrecoverable:
+		default:
+		}
+		stop()
+		return
+	}
+
+	if isTransientWatchError(err) {
+		logger.Warningf("transient error watching swift resources, reflector will re-list: %+v", err)
+		return
+	}
+
+	cache.DefaultWatchErrorHandler(r, err)
+}
+
+// UnrecoverableWatchError indicates that StartWatch's underlying watch hit an error it cannot
+// recover from on its own (e.g. the CRD was uninstalled, or RBAC forbids list/watch). The parent
+// operator should cancel its context and restart this controller from scratch.
+type UnrecoverableWatchError struct {
+	ResName string
+	Err     error
+}
+
+func (e *UnrecoverableWatchError) Error() string {
+	return fmt.Sprintf("unrecoverable error watching %s: %+v", e.ResName, e.Err)
+}
+
+// isTransientWatchError reports whether err is expected to clear up on its own after a re-list,
+// e.g. a dropped connection or an expired resourceVersion.
+func isTransientWatchError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) {
+		return true
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+		return true
+	}
+	return false
+}
+
+// isFatalWatchError reports whether err indicates a condition the controller cannot recover
+// from without operator intervention: missing RBAC, an uninstalled CRD, or a decoder failure.
+func isFatalWatchError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return apierrors.IsForbidden(err) || apierrors.IsNotFound(err) ||
+		runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) || runtime.IsMissingVersion(err)
 }
 
 func (c *SWIFTController) onAdd(obj interface{}) {
@@ -110,6 +261,19 @@ func (c *SWIFTController) onAdd(obj interface{}) {
 		return
 	}
 
+	if !c.managesObject(swift) {
+		logger.Debugf("swift %s no longer matches label selector %s, ignoring", swift.Name, c.labelSelector)
+		return
+	}
+
+	if !canCreateOrUpdate(swift.Spec.ManagementPolicy) {
+		logger.Infof("management policy %s for swift %s is observe-only, adopting existing resources without mutation", swift.Spec.ManagementPolicy, swift.Name)
+		if err = c.updateObserveStatus(swift); err != nil {
+			logger.Errorf("failed to update observe status for swift %s: %+v", swift.Name, err)
+		}
+		return
+	}
+
 	if err = c.CreateService(*swift, c.serviceOwners(swift)); err != nil {
 		logger.Errorf("failed to create swift %s. %+v", swift.Name, err)
 	}
@@ -127,6 +291,19 @@ func (c *SWIFTController) onUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	if !c.managesObject(newService) {
+		logger.Debugf("swift %s no longer matches label selector %s, ignoring", newService.Name, c.labelSelector)
+		return
+	}
+
+	if !canCreateOrUpdate(newService.Spec.ManagementPolicy) {
+		logger.Infof("management policy %s for swift %s is observe-only, skipping update", newService.Spec.ManagementPolicy, newService.Name)
+		if err = c.updateObserveStatus(newService); err != nil {
+			logger.Errorf("failed to update observe status for swift %s: %+v", newService.Name, err)
+		}
+		return
+	}
+
 	if !serviceChanged(oldService.Spec, newService.Spec) {
 		logger.Debugf("swift %s did not change", newService.Name)
 		return
@@ -145,11 +322,87 @@ func (c *SWIFTController) onDelete(obj interface{}) {
 		return
 	}
 
+	if !c.managesObject(swift) {
+		logger.Debugf("swift %s no longer matches label selector %s, ignoring", swift.Name, c.labelSelector)
+		return
+	}
+
+	if !canDelete(swift.Spec.ManagementPolicy) {
+		logger.Infof("management policy %s for swift %s skips delete cleanup", swift.Spec.ManagementPolicy, swift.Name)
+		return
+	}
+
+	if swift.Spec.PreserveResourcesOnDeletion != nil && *swift.Spec.PreserveResourcesOnDeletion {
+		logger.Infof("preserveResourcesOnDeletion is set for swift %s, leaving gateway resources in place", swift.Name)
+		if err = c.releaseServiceOwnership(*swift); err != nil {
+			logger.Errorf("failed to release ownership of swift %s resources: %+v", swift.Name, err)
+		}
+		return
+	}
+
 	if err = c.DeleteService(*swift); err != nil {
 		logger.Errorf("failed to delete swift %s. %+v", swift.Name, err)
 	}
 }
 
+// canCreateOrUpdate reports whether the given management policy allows the controller to create
+// or mutate the SWIFT gateway resources. ManagementPolicyDefault and ManagementPolicyObserveCreateUpdate
+// allow it; ManagementPolicyObserve and ManagementPolicyObserveDelete are observe-only for creation/updates.
+func canCreateOrUpdate(policy edgefsv1alpha1.ManagementPolicy) bool {
+	switch policy {
+	case edgefsv1alpha1.ManagementPolicyObserve, edgefsv1alpha1.ManagementPolicyObserveDelete:
+		return false
+	default:
+		return true
+	}
+}
+
+// canDelete reports whether the given management policy allows the controller to clean up the
+// SWIFT gateway resources when the CR is removed. ManagementPolicyDefault and
+// ManagementPolicyObserveDelete allow it; ManagementPolicyObserve and ManagementPolicyObserveCreateUpdate
+// leave externally-managed or detached resources alone.
+func canDelete(policy edgefsv1alpha1.ManagementPolicy) bool {
+	switch policy {
+	case edgefsv1alpha1.ManagementPolicyObserve, edgefsv1alpha1.ManagementPolicyObserveCreateUpdate:
+		return false
+	default:
+		return true
+	}
+}
+
+// updateObserveStatus reports, via a regular Update of swift's Status field, that the controller is adopting
+// its existing gateway resources in observe-only mode. It's a no-op for any policy other than
+// ManagementPolicyObserve, since ManagementPolicyObserveDelete and ManagementPolicyObserveCreateUpdate
+// don't claim full observe-only adoption semantics.
+func (c *SWIFTController) updateObserveStatus(swift *edgefsv1alpha1.SWIFT) error {
+	if swift.Spec.ManagementPolicy != edgefsv1alpha1.ManagementPolicyObserve {
+		return nil
+	}
+
+	existing, err := c.context.RookClientset.EdgefsV1alpha1().SWIFTs(swift.Namespace).Get(swift.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get swift %s to update status: %+v", swift.Name, err)
+	}
+
+	existing.Status = edgefsv1alpha1.SWIFTStatus{
+		Phase:   edgefsv1alpha1.SWIFTPhaseObserved,
+		Message: "adopted by controller under ManagementPolicyObserve; gateway resources are not managed",
+	}
+	if _, err := c.context.RookClientset.EdgefsV1alpha1().SWIFTs(swift.Namespace).Update(existing); err != nil {
+		return fmt.Errorf("failed to update status for swift %s: %+v", swift.Name, err)
+	}
+	return nil
+}
+
+// managesObject reports whether swift's labels match c.labelSelector, i.e. whether this
+// controller instance is responsible for reconciling it. listWatch already restricts what this
+// controller instance lists and watches, so in steady state this should always be true; it
+// remains as a defensive check against swift's labels changing between being cached and the
+// handler running.
+func (c *SWIFTController) managesObject(swift *edgefsv1alpha1.SWIFT) bool {
+	return c.labelSelector.Matches(labels.Set(swift.Labels))
+}
+
 func (c *SWIFTController) serviceOwners(service *edgefsv1alpha1.SWIFT) []metav1.OwnerReference {
 	// Only set the cluster crd as the owner of the SWIFT resources.
 	// If the SWIFT crd is deleted, the operator will explicitly remove the SWIFT resources.
@@ -158,7 +411,121 @@ func (c *SWIFTController) serviceOwners(service *edgefsv1alpha1.SWIFT) []metav1.
 	return []metav1.OwnerReference{c.ownerRef}
 }
 
+// releaseServiceOwnership strips the operator's OwnerReference from the StatefulSet, Service and
+// ConfigMap backing the given swift resource instead of deleting them. This allows the SWIFT
+// gateway pods to keep serving traffic after the CR is removed, e.g. while migrating to a new
+// controller/cluster or rolling back an upgrade.
+func (c *SWIFTController) releaseServiceOwnership(swift edgefsv1alpha1.SWIFT) error {
+	ns := swift.Namespace
+	name := instanceName(swift.Name)
+
+	statefulSet, err := c.context.Clientset.AppsV1().StatefulSets(ns).Get(name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		logger.Debugf("statefulset %s already gone, nothing to release", name)
+	case err != nil:
+		return fmt.Errorf("failed to get statefulset %s to release ownership: %+v", name, err)
+	default:
+		statefulSet.OwnerReferences = removeOwnerRef(statefulSet.OwnerReferences, c.ownerRef)
+		if _, err := c.context.Clientset.AppsV1().StatefulSets(ns).Update(statefulSet); err != nil {
+			return fmt.Errorf("failed to update statefulset %s: %+v", name, err)
+		}
+	}
+
+	service, err := c.context.Clientset.CoreV1().Services(ns).Get(name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		logger.Debugf("service %s already gone, nothing to release", name)
+	case err != nil:
+		return fmt.Errorf("failed to get service %s to release ownership: %+v", name, err)
+	default:
+		service.OwnerReferences = removeOwnerRef(service.OwnerReferences, c.ownerRef)
+		if _, err := c.context.Clientset.CoreV1().Services(ns).Update(service); err != nil {
+			return fmt.Errorf("failed to update service %s: %+v", name, err)
+		}
+	}
+
+	configMap, err := c.context.Clientset.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		logger.Debugf("configmap %s already gone, nothing to release", name)
+	case err != nil:
+		return fmt.Errorf("failed to get configmap %s to release ownership: %+v", name, err)
+	default:
+		configMap.OwnerReferences = removeOwnerRef(configMap.OwnerReferences, c.ownerRef)
+		if _, err := c.context.Clientset.CoreV1().ConfigMaps(ns).Update(configMap); err != nil {
+			return fmt.Errorf("failed to update configmap %s: %+v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// removeOwnerRef returns refs with any entry matching owner (by UID) removed.
+func removeOwnerRef(refs []metav1.OwnerReference, owner metav1.OwnerReference) []metav1.OwnerReference {
+	result := make([]metav1.OwnerReference, 0, len(refs))
+	for _, ref := range refs {
+		if ref.UID != owner.UID {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
 func serviceChanged(oldService, newService edgefsv1alpha1.SWIFTSpec) bool {
+	var diff string
+	changed := false
+
+	if oldService.Instances != newService.Instances {
+		changed = true
+		diff = "instances"
+	} else if oldService.Port != newService.Port || oldService.SecurePort != newService.SecurePort {
+		changed = true
+		diff = "ports"
+	} else if !reflect.DeepEqual(oldService.Options, newService.Options) {
+		changed = true
+		diff = "options"
+	} else if !reflect.DeepEqual(oldService.Placement, newService.Placement) {
+		changed = true
+		diff = "placement"
+	} else if resourcesChanged(oldService.Resources, newService.Resources) {
+		changed = true
+		diff = "resources"
+	} else if oldService.ResourceProfile != newService.ResourceProfile {
+		changed = true
+		diff = "resourceProfile"
+	} else if oldService.ChunkCacheSize.Cmp(newService.ChunkCacheSize) != 0 {
+		changed = true
+		diff = "chunkCacheSize"
+	}
+
+	if changed {
+		logger.Infof("swift spec changed in %s, updating swift service", diff)
+	}
+
+	return changed
+}
+
+// resourcesChanged compares every entry of ResourceRequirements' Requests and Limits, so changes
+// to any resource key (cpu, memory, ephemeral-storage, extended/GPU resources, etc.) are detected,
+// not just cpu/memory.
+func resourcesChanged(oldResources, newResources v1.ResourceRequirements) bool {
+	return resourceListChanged(oldResources.Requests, newResources.Requests) ||
+		resourceListChanged(oldResources.Limits, newResources.Limits)
+}
+
+// resourceListChanged reports whether oldList and newList differ in either their set of resource
+// keys or the quantity for any shared key.
+func resourceListChanged(oldList, newList v1.ResourceList) bool {
+	if len(oldList) != len(newList) {
+		return true
+	}
+	for name, oldQuantity := range oldList {
+		newQuantity, ok := newList[name]
+		if !ok || oldQuantity.Cmp(newQuantity) != 0 {
+			return true
+		}
+	}
 	return false
 }
 
@@ -0,0 +1,387 @@
+/*
+Copyright 2016 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swift
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	edgefsv1alpha1 "github.com/rook/rook/pkg/apis/edgefs.rook.io/v1alpha1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func baseSWIFTSpec() edgefsv1alpha1.SWIFTSpec {
+	return edgefsv1alpha1.SWIFTSpec{
+		Instances:       1,
+		Port:            8080,
+		SecurePort:      8443,
+		Options:         map[string]string{"foo": "bar"},
+		Placement:       rookalpha.Placement{},
+		Resources:       v1.ResourceRequirements{},
+		ResourceProfile: "embedded",
+		ChunkCacheSize:  resource.MustParse("1Gi"),
+	}
+}
+
+func TestServiceChanged_NoOpWhenUnchanged(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	newSpec := baseSWIFTSpec()
+
+	assert.False(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestServiceChanged_Instances(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	newSpec := baseSWIFTSpec()
+	newSpec.Instances = 3
+
+	assert.True(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestServiceChanged_Ports(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	newSpec := baseSWIFTSpec()
+	newSpec.Port = 9090
+
+	assert.True(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestServiceChanged_Options(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	newSpec := baseSWIFTSpec()
+	newSpec.Options = map[string]string{"foo": "baz"}
+
+	assert.True(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestServiceChanged_Placement(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	newSpec := baseSWIFTSpec()
+	newSpec.Placement = rookalpha.Placement{
+		NodeAffinity: &v1.NodeAffinity{},
+	}
+
+	assert.True(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestServiceChanged_Resources(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	newSpec := baseSWIFTSpec()
+	newSpec.Resources = v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU: resource.MustParse("500m"),
+		},
+	}
+
+	assert.True(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestServiceChanged_ResourcesNonCPUMemoryKey(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	oldSpec.Resources = v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+		},
+	}
+	newSpec := baseSWIFTSpec()
+	newSpec.Resources = v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+		},
+	}
+
+	assert.True(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestServiceChanged_ResourceProfile(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	newSpec := baseSWIFTSpec()
+	newSpec.ResourceProfile = "performance"
+
+	assert.True(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestServiceChanged_ChunkCacheSize(t *testing.T) {
+	oldSpec := baseSWIFTSpec()
+	newSpec := baseSWIFTSpec()
+	newSpec.ChunkCacheSize = resource.MustParse("2Gi")
+
+	assert.True(t, serviceChanged(oldSpec, newSpec))
+}
+
+func TestInstanceName(t *testing.T) {
+	assert.Equal(t, "rook-edgefs-swift-my-swift", instanceName("my-swift"))
+	assert.NotEqual(t, instanceName("swift-a"), instanceName("swift-b"))
+}
+
+func TestRemoveOwnerRef(t *testing.T) {
+	owner := metav1.OwnerReference{UID: types.UID("owner-uid"), Name: "cluster"}
+	other := metav1.OwnerReference{UID: types.UID("other-uid"), Name: "other"}
+
+	refs := removeOwnerRef([]metav1.OwnerReference{owner, other}, owner)
+
+	assert.Len(t, refs, 1)
+	assert.Equal(t, other, refs[0])
+}
+
+func TestRemoveOwnerRef_NotPresent(t *testing.T) {
+	owner := metav1.OwnerReference{UID: types.UID("owner-uid"), Name: "cluster"}
+	other := metav1.OwnerReference{UID: types.UID("other-uid"), Name: "other"}
+
+	refs := removeOwnerRef([]metav1.OwnerReference{other}, owner)
+
+	assert.Len(t, refs, 1)
+	assert.Equal(t, other, refs[0])
+}
+
+func TestReleaseServiceOwnership(t *testing.T) {
+	ns := "rook-edgefs"
+	owner := metav1.OwnerReference{UID: types.UID("cluster-uid"), Name: "cluster"}
+	other := metav1.OwnerReference{UID: types.UID("other-uid"), Name: "other"}
+	name := instanceName("my-swift")
+
+	clientset := fake.NewSimpleClientset(
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, OwnerReferences: []metav1.OwnerReference{owner, other}}},
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, OwnerReferences: []metav1.OwnerReference{owner}}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, OwnerReferences: []metav1.OwnerReference{owner}}},
+	)
+
+	c := &SWIFTController{context: &clusterd.Context{Clientset: clientset}, ownerRef: owner}
+	swift := edgefsv1alpha1.SWIFT{ObjectMeta: metav1.ObjectMeta{Name: "my-swift", Namespace: ns}}
+
+	err := c.releaseServiceOwnership(swift)
+	assert.NoError(t, err)
+
+	statefulSet, err := clientset.AppsV1().StatefulSets(ns).Get(name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []metav1.OwnerReference{other}, statefulSet.OwnerReferences)
+
+	service, err := clientset.CoreV1().Services(ns).Get(name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, service.OwnerReferences)
+
+	configMap, err := clientset.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, configMap.OwnerReferences)
+}
+
+func TestReleaseServiceOwnership_AlreadyGoneIsNotAnError(t *testing.T) {
+	c := &SWIFTController{context: &clusterd.Context{Clientset: fake.NewSimpleClientset()}, ownerRef: metav1.OwnerReference{UID: types.UID("cluster-uid")}}
+	swift := edgefsv1alpha1.SWIFT{ObjectMeta: metav1.ObjectMeta{Name: "my-swift", Namespace: "rook-edgefs"}}
+
+	assert.NoError(t, c.releaseServiceOwnership(swift))
+}
+
+func TestCanCreateOrUpdate(t *testing.T) {
+	assert.True(t, canCreateOrUpdate(edgefsv1alpha1.ManagementPolicyDefault))
+	assert.True(t, canCreateOrUpdate(edgefsv1alpha1.ManagementPolicyObserveCreateUpdate))
+	assert.False(t, canCreateOrUpdate(edgefsv1alpha1.ManagementPolicyObserveDelete))
+	assert.False(t, canCreateOrUpdate(edgefsv1alpha1.ManagementPolicyObserve))
+}
+
+func TestUpdateObserveStatus_NoOpForNonObservePolicy(t *testing.T) {
+	c := &SWIFTController{}
+
+	for _, policy := range []edgefsv1alpha1.ManagementPolicy{
+		edgefsv1alpha1.ManagementPolicyDefault,
+		edgefsv1alpha1.ManagementPolicyObserveCreateUpdate,
+		edgefsv1alpha1.ManagementPolicyObserveDelete,
+	} {
+		swift := &edgefsv1alpha1.SWIFT{Spec: edgefsv1alpha1.SWIFTSpec{ManagementPolicy: policy}}
+		assert.NoError(t, c.updateObserveStatus(swift))
+	}
+}
+
+func TestCanDelete(t *testing.T) {
+	assert.True(t, canDelete(edgefsv1alpha1.ManagementPolicyDefault))
+	assert.True(t, canDelete(edgefsv1alpha1.ManagementPolicyObserveDelete))
+	assert.False(t, canDelete(edgefsv1alpha1.ManagementPolicyObserveCreateUpdate))
+	assert.False(t, canDelete(edgefsv1alpha1.ManagementPolicyObserve))
+}
+
+func TestManagesObject_MatchingSelector(t *testing.T) {
+	c := &SWIFTController{labelSelector: labels.SelectorFromSet(labels.Set{"rook.io/shard": "1"})}
+	swift := &edgefsv1alpha1.SWIFT{
+		ObjectMeta: metav1.ObjectMeta{Name: "swift-sample", Labels: map[string]string{"rook.io/shard": "1"}},
+	}
+
+	assert.True(t, c.managesObject(swift))
+}
+
+func TestManagesObject_NonMatchingSelector(t *testing.T) {
+	c := &SWIFTController{labelSelector: labels.SelectorFromSet(labels.Set{"rook.io/shard": "1"})}
+	swift := &edgefsv1alpha1.SWIFT{
+		ObjectMeta: metav1.ObjectMeta{Name: "swift-sample", Labels: map[string]string{"rook.io/shard": "2"}},
+	}
+
+	assert.False(t, c.managesObject(swift))
+}
+
+func TestManagesObject_EverythingSelectorMatchesUnlabeled(t *testing.T) {
+	c := &SWIFTController{labelSelector: labels.Everything()}
+	swift := &edgefsv1alpha1.SWIFT{ObjectMeta: metav1.ObjectMeta{Name: "swift-sample"}}
+
+	assert.True(t, c.managesObject(swift))
+}
+
+func TestNewSWIFTController_DefaultsLabelSelector(t *testing.T) {
+	c := NewSWIFTController(
+		nil, "rook/edgefs:latest", false, "/var/lib/edgefs",
+		resource.MustParse("1Gi"), rookalpha.Placement{}, v1.ResourceRequirements{},
+		"embedded", metav1.OwnerReference{}, nil,
+	)
+
+	assert.Equal(t, labels.Everything(), c.labelSelector)
+}
+
+func TestNewSWIFTController_RetainsProvidedLabelSelector(t *testing.T) {
+	selector := labels.SelectorFromSet(labels.Set{"rook.io/shard": "1"})
+
+	c := NewSWIFTController(
+		nil, "rook/edgefs:latest", false, "/var/lib/edgefs",
+		resource.MustParse("1Gi"), rookalpha.Placement{}, v1.ResourceRequirements{},
+		"embedded", metav1.OwnerReference{}, selector,
+	)
+
+	assert.Equal(t, selector, c.labelSelector)
+}
+
+func TestIsTransientWatchError(t *testing.T) {
+	gone := apierrors.NewGone("swift resource is gone")
+	expired := apierrors.NewResourceExpired("resourceVersion too old")
+
+	assert.True(t, isTransientWatchError(gone))
+	assert.True(t, isTransientWatchError(expired))
+	assert.False(t, isTransientWatchError(nil))
+}
+
+func TestIsFatalWatchError(t *testing.T) {
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Group: edgefsv1alpha1.CustomResourceGroup, Resource: customResourceNamePlural}, "swift-sample", nil)
+	notFound := apierrors.NewNotFound(schema.GroupResource{Group: edgefsv1alpha1.CustomResourceGroup, Resource: customResourceNamePlural}, customResourceNamePlural)
+
+	assert.True(t, isFatalWatchError(forbidden))
+	assert.True(t, isFatalWatchError(notFound))
+	assert.False(t, isFatalWatchError(nil))
+}
+
+func TestIsTransientWatchError_DoesNotOverlapFatal(t *testing.T) {
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Group: edgefsv1alpha1.CustomResourceGroup, Resource: customResourceNamePlural}, "swift-sample", nil)
+
+	assert.False(t, isTransientWatchError(forbidden))
+}
+
+func TestUnrecoverableWatchError_Error(t *testing.T) {
+	err := &UnrecoverableWatchError{ResName: customResourceName, Err: apierrors.NewForbidden(schema.GroupResource{Resource: customResourceNamePlural}, "swift-sample", nil)}
+
+	assert.Contains(t, err.Error(), customResourceName)
+}
+
+func TestHandleWatchError_FatalReportsOnceAndStops(t *testing.T) {
+	c := &SWIFTController{}
+	errCh := make(chan error, 1)
+	stopped := 0
+	stop := func() { stopped++ }
+
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Group: edgefsv1alpha1.CustomResourceGroup, Resource: customResourceNamePlural}, "swift-sample", nil)
+	c.handleWatchError(nil, forbidden, errCh, stop)
+
+	select {
+	case err := <-errCh:
+		unrecoverable, ok := err.(*UnrecoverableWatchError)
+		assert.True(t, ok)
+		assert.Contains(t, unrecoverable.Error(), customResourceName)
+	default:
+		t.Fatal("expected a fatal error to be reported on errCh")
+	}
+	assert.Equal(t, 1, stopped)
+}
+
+func TestHandleWatchError_TransientDoesNotReportOrStop(t *testing.T) {
+	c := &SWIFTController{}
+	errCh := make(chan error, 1)
+	stopped := 0
+	stop := func() { stopped++ }
+
+	gone := apierrors.NewGone("swift resource is gone")
+	c.handleWatchError(nil, gone, errCh, stop)
+
+	select {
+	case <-errCh:
+		t.Fatal("transient error should not be reported on errCh")
+	default:
+	}
+	assert.Equal(t, 0, stopped)
+}
+
+// TestStartWatch_FatalWatchErrorStopsInformer drives a real cache.SharedIndexInformer, wired up the
+// same way as in StartWatch, whose watch request fails with Forbidden, the way an apiserver would
+// report RBAC-denied watch access. It asserts that the SetWatchErrorHandler actually observes the
+// error (rather than relying on a blocking Watch call that never returns it) and stops the informer.
+func TestStartWatch_FatalWatchErrorStopsInformer(t *testing.T) {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &edgefsv1alpha1.SWIFTList{}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, apierrors.NewForbidden(schema.GroupResource{Group: edgefsv1alpha1.CustomResourceGroup, Resource: customResourceNamePlural}, "", nil)
+		},
+	}
+
+	c := &SWIFTController{labelSelector: labels.Everything()}
+	informer := cache.NewSharedIndexInformer(lw, &edgefsv1alpha1.SWIFT{}, 0, cache.Indexers{})
+
+	errCh := make(chan error, 1)
+	informerStopCh := make(chan struct{})
+	var stopOnce sync.Once
+	stop := func() { stopOnce.Do(func() { close(informerStopCh) }) }
+
+	err := informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		c.handleWatchError(r, err, errCh, stop)
+	})
+	assert.NoError(t, err)
+
+	go informer.Run(informerStopCh)
+
+	select {
+	case err := <-errCh:
+		_, ok := err.(*UnrecoverableWatchError)
+		assert.True(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a fatal error on errCh after the Forbidden watch request")
+	}
+
+	select {
+	case <-informerStopCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the informer to be stopped after a fatal watch error")
+	}
+}